@@ -0,0 +1,22 @@
+package main
+
+import (
+	"log"
+	"net"
+
+	"github.com/douglasmakey/send-file-over-tcp-demo/protocol"
+)
+
+func main() {
+	// Connect to the sender
+	conn, err := net.Dial("tcp", "x.x.x.x:3000")
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer conn.Close()
+
+	// Receive the offered files into the local directory
+	if err := protocol.Receive(conn, "."); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net"
+
+	"github.com/douglasmakey/send-file-over-tcp-demo/protocol"
+)
+
+func main() {
+	// Create the listener
+	listener, err := net.Listen("tcp", ":3000")
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	defer listener.Close()
+
+	for {
+		// Wait for a client to connect
+		conn, err := listener.Accept()
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		// Offer and send the files under ../dummy to the client
+		go func() {
+			defer conn.Close()
+
+			if err := protocol.Send(conn, "../dummy"); err != nil {
+				fmt.Println(err)
+			}
+		}()
+	}
+}
@@ -0,0 +1,258 @@
+package protocol
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/hmac"
+	crand "crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"os"
+)
+
+// encryptedChunkSize is the amount of plaintext sealed into a single
+// AES-GCM frame.
+const encryptedChunkSize = 64 * 1024
+
+// noncePrefixSize is the length, in bytes, of the random prefix sent once
+// per connection; the remaining 4 bytes of the 12-byte GCM nonce are a
+// per-chunk big-endian counter.
+const noncePrefixSize = 8
+
+var hkdfInfo = []byte("send-file-over-tcp-demo aes-gcm")
+
+// newCipher derives an AES-256-GCM AEAD from an X25519 shared secret using
+// HKDF-SHA256.
+func newCipher(sharedSecret []byte) (cipher.AEAD, error) {
+	key := hkdfSHA256(sharedSecret, hkdfInfo, 32)
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
+
+// hkdfSHA256 implements RFC 5869 HKDF-Extract-then-Expand with an empty
+// salt, producing length bytes of key material.
+func hkdfSHA256(secret, info []byte, length int) []byte {
+	extract := hmac.New(sha256.New, make([]byte, sha256.Size))
+	extract.Write(secret)
+	prk := extract.Sum(nil)
+
+	var (
+		out  []byte
+		prev []byte
+		ctr  byte = 1
+	)
+	for len(out) < length {
+		mac := hmac.New(sha256.New, prk)
+		mac.Write(prev)
+		mac.Write(info)
+		mac.Write([]byte{ctr})
+		prev = mac.Sum(nil)
+		out = append(out, prev...)
+		ctr++
+	}
+
+	return out[:length]
+}
+
+// handshakeSender runs the sender side of the X25519 key exchange: generate
+// an ephemeral key pair and a random nonce prefix, send both, receive the
+// peer's public key, and derive the shared AEAD. Like a bare Diffie-Hellman
+// exchange, this authenticates neither side against the other, so it only
+// protects against a passive eavesdropper, not an active man-in-the-middle.
+func handshakeSender(conn net.Conn) (cipher.AEAD, [noncePrefixSize]byte, error) {
+	var noncePrefix [noncePrefixSize]byte
+	if _, err := io.ReadFull(crand.Reader, noncePrefix[:]); err != nil {
+		return nil, noncePrefix, err
+	}
+
+	priv, err := ecdh.X25519().GenerateKey(crand.Reader)
+	if err != nil {
+		return nil, noncePrefix, err
+	}
+
+	payload := make([]byte, 32+noncePrefixSize)
+	copy(payload, priv.PublicKey().Bytes())
+	copy(payload[32:], noncePrefix[:])
+	if err := writePacket(conn, PacketKeyExchange, payload); err != nil {
+		return nil, noncePrefix, err
+	}
+
+	kind, payload, err := readPacket(conn)
+	if err != nil {
+		return nil, noncePrefix, err
+	}
+	if kind != PacketKeyExchange || len(payload) != 32 {
+		return nil, noncePrefix, fmt.Errorf("protocol: malformed key exchange reply")
+	}
+
+	peerPub, err := ecdh.X25519().NewPublicKey(payload)
+	if err != nil {
+		return nil, noncePrefix, err
+	}
+
+	secret, err := priv.ECDH(peerPub)
+	if err != nil {
+		return nil, noncePrefix, err
+	}
+
+	aead, err := newCipher(secret)
+	return aead, noncePrefix, err
+}
+
+// handshakeReceiver runs the receiver side of the same exchange.
+func handshakeReceiver(conn net.Conn) (cipher.AEAD, [noncePrefixSize]byte, error) {
+	var noncePrefix [noncePrefixSize]byte
+
+	kind, payload, err := readPacket(conn)
+	if err != nil {
+		return nil, noncePrefix, err
+	}
+	if kind != PacketKeyExchange || len(payload) != 32+noncePrefixSize {
+		return nil, noncePrefix, fmt.Errorf("protocol: malformed key exchange")
+	}
+
+	peerPub, err := ecdh.X25519().NewPublicKey(payload[:32])
+	if err != nil {
+		return nil, noncePrefix, err
+	}
+	copy(noncePrefix[:], payload[32:])
+
+	priv, err := ecdh.X25519().GenerateKey(crand.Reader)
+	if err != nil {
+		return nil, noncePrefix, err
+	}
+
+	if err := writePacket(conn, PacketKeyExchange, priv.PublicKey().Bytes()); err != nil {
+		return nil, noncePrefix, err
+	}
+
+	secret, err := priv.ECDH(peerPub)
+	if err != nil {
+		return nil, noncePrefix, err
+	}
+
+	aead, err := newCipher(secret)
+	return aead, noncePrefix, err
+}
+
+// chunkNonce builds the 12-byte GCM nonce for a chunk: the connection's
+// random prefix followed by a big-endian chunk counter, then advances
+// counter for the next chunk. It errors instead of wrapping counter back to
+// an already-used value, which would reuse a nonce under the same key.
+func chunkNonce(prefix [noncePrefixSize]byte, counter *uint32) ([]byte, error) {
+	if *counter == math.MaxUint32 {
+		return nil, errors.New("protocol: encrypted transfer exceeds the maximum chunk count for one connection")
+	}
+
+	nonce := make([]byte, noncePrefixSize+4)
+	copy(nonce, prefix[:])
+	binary.BigEndian.PutUint32(nonce[noncePrefixSize:], *counter)
+	*counter++
+	return nonce, nil
+}
+
+// EncryptedTransport seals each chunk of a file with AES-256-GCM before
+// writing it to conn as a [4-byte length][ciphertext||tag] frame. It
+// requires a prior handshakeSender/handshakeReceiver to agree on aead and
+// noncePrefix, and a Counter shared across every file sent on the
+// connection so no nonce is ever reused. Because AES-GCM needs the plaintext
+// in userspace, this transport is mutually exclusive with SendfileTransport.
+type EncryptedTransport struct {
+	AEAD        cipher.AEAD
+	NoncePrefix [noncePrefixSize]byte
+	Counter     *uint32
+}
+
+func (t EncryptedTransport) Send(conn net.Conn, file *os.File, offset, size int64) error {
+	if _, err := file.Seek(offset, io.SeekStart); err != nil {
+		return err
+	}
+
+	buf := make([]byte, encryptedChunkSize)
+	lenBuf := make([]byte, 4)
+	remaining := size
+	for remaining > 0 {
+		n := int64(len(buf))
+		if remaining < n {
+			n = remaining
+		}
+
+		if _, err := io.ReadFull(file, buf[:n]); err != nil {
+			return err
+		}
+
+		nonce, err := chunkNonce(t.NoncePrefix, t.Counter)
+		if err != nil {
+			return err
+		}
+
+		sealed := t.AEAD.Seal(nil, nonce, buf[:n], nil)
+
+		binary.LittleEndian.PutUint32(lenBuf, uint32(len(sealed)))
+		if _, err := conn.Write(lenBuf); err != nil {
+			return err
+		}
+		if _, err := conn.Write(sealed); err != nil {
+			return err
+		}
+
+		remaining -= n
+	}
+
+	return nil
+}
+
+// maxSealedChunkSize bounds the frames receiveEncrypted will allocate for:
+// a chunk of at most encryptedChunkSize plaintext bytes plus the GCM tag.
+const maxSealedChunkSize = encryptedChunkSize + 16
+
+// receiveEncrypted reads size plaintext bytes worth of sealed chunks from
+// conn, decrypts them and writes the plaintext to w.
+func receiveEncrypted(conn net.Conn, w io.Writer, size int64, aead cipher.AEAD, noncePrefix [noncePrefixSize]byte, counter *uint32) error {
+	lenBuf := make([]byte, 4)
+	written := int64(0)
+	for written < size {
+		if _, err := io.ReadFull(conn, lenBuf); err != nil {
+			return err
+		}
+
+		sealedLen := binary.LittleEndian.Uint32(lenBuf)
+		if sealedLen > maxSealedChunkSize {
+			return fmt.Errorf("protocol: encrypted chunk of %d bytes exceeds the %d byte limit", sealedLen, maxSealedChunkSize)
+		}
+
+		sealed := make([]byte, sealedLen)
+		if _, err := io.ReadFull(conn, sealed); err != nil {
+			return err
+		}
+
+		nonce, err := chunkNonce(noncePrefix, counter)
+		if err != nil {
+			return err
+		}
+
+		plaintext, err := aead.Open(nil, nonce, sealed, nil)
+		if err != nil {
+			return fmt.Errorf("%w: %v", ErrDecryptionFailed, err)
+		}
+
+		if _, err := w.Write(plaintext); err != nil {
+			return err
+		}
+
+		written += int64(len(plaintext))
+	}
+
+	return nil
+}
@@ -0,0 +1,42 @@
+package protocol
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"testing"
+)
+
+func TestChunkNonce(t *testing.T) {
+	var prefix [noncePrefixSize]byte
+	copy(prefix[:], "abcdefgh")
+
+	counter := uint32(0)
+	for i := uint32(0); i < 3; i++ {
+		nonce, err := chunkNonce(prefix, &counter)
+		if err != nil {
+			t.Fatalf("chunkNonce at counter %d: %v", i, err)
+		}
+		if !bytes.Equal(nonce[:noncePrefixSize], prefix[:]) {
+			t.Fatalf("nonce prefix = %x, want %x", nonce[:noncePrefixSize], prefix[:])
+		}
+		if got := binary.BigEndian.Uint32(nonce[noncePrefixSize:]); got != i {
+			t.Fatalf("nonce counter = %d, want %d", got, i)
+		}
+	}
+	if counter != 3 {
+		t.Fatalf("counter after 3 calls = %d, want 3", counter)
+	}
+}
+
+func TestChunkNonceRejectsCounterWraparound(t *testing.T) {
+	var prefix [noncePrefixSize]byte
+	counter := uint32(math.MaxUint32)
+
+	if _, err := chunkNonce(prefix, &counter); err == nil {
+		t.Fatal("chunkNonce at math.MaxUint32 = nil error, want an error instead of wrapping the counter back to a reused value")
+	}
+	if counter != math.MaxUint32 {
+		t.Fatalf("counter after a rejected call = %d, want it left at %d", counter, uint32(math.MaxUint32))
+	}
+}
@@ -0,0 +1,18 @@
+package protocol
+
+import "errors"
+
+var (
+	// ErrChecksumMismatch is returned when a received file's SHA-256 digest
+	// does not match the digest promised in the transfer offer.
+	ErrChecksumMismatch = errors.New("protocol: checksum mismatch")
+	// ErrOfferRejected is returned to the sender when the receiver declines
+	// a transfer offer.
+	ErrOfferRejected = errors.New("protocol: offer rejected")
+	// ErrUnexpectedPacket is returned when a packet of an unexpected type
+	// is received at a given point in the protocol.
+	ErrUnexpectedPacket = errors.New("protocol: unexpected packet type")
+	// ErrDecryptionFailed is returned when an AES-GCM chunk fails to
+	// authenticate in encrypted mode.
+	ErrDecryptionFailed = errors.New("protocol: decryption failed")
+)
@@ -0,0 +1,200 @@
+package protocol
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"io/fs"
+	"path"
+	"strings"
+)
+
+// FileInfo describes a single file within a TransferOffer.
+type FileInfo struct {
+	// Path is the file's path relative to the transfer root, using "/" as
+	// the separator regardless of the host OS.
+	Path   string
+	Size   int64
+	Mode   fs.FileMode
+	SHA256 [sha256.Size]byte
+}
+
+// TransferOffer is the tree of files a sender proposes to transfer.
+type TransferOffer struct {
+	Files []FileInfo
+	// Encrypted requests the AES-256-GCM transport, negotiated with an
+	// X25519/HKDF-SHA256 handshake right after the offer is accepted.
+	Encrypted bool
+}
+
+// encode serializes the offer: a 1-byte Encrypted flag, a uint32 file count
+// and then, per file, a uint16 path length, the path bytes, a uint64 size, a
+// uint32 mode and the 32-byte SHA-256 digest.
+func (o *TransferOffer) encode() []byte {
+	size := 1 + 4
+	for _, f := range o.Files {
+		size += 2 + len(f.Path) + 8 + 4 + sha256.Size
+	}
+
+	buf := make([]byte, size)
+	pos := 0
+	if o.Encrypted {
+		buf[pos] = 1
+	}
+	pos++
+	binary.LittleEndian.PutUint32(buf[pos:], uint32(len(o.Files)))
+	pos += 4
+
+	for _, f := range o.Files {
+		binary.LittleEndian.PutUint16(buf[pos:], uint16(len(f.Path)))
+		pos += 2
+		pos += copy(buf[pos:], f.Path)
+		binary.LittleEndian.PutUint64(buf[pos:], uint64(f.Size))
+		pos += 8
+		binary.LittleEndian.PutUint32(buf[pos:], uint32(f.Mode))
+		pos += 4
+		pos += copy(buf[pos:], f.SHA256[:])
+	}
+
+	return buf
+}
+
+// decodeTransferOffer is the inverse of (*TransferOffer).encode.
+func decodeTransferOffer(payload []byte) (*TransferOffer, error) {
+	if len(payload) < 1+4 {
+		return nil, fmt.Errorf("protocol: truncated transfer offer")
+	}
+
+	offer := &TransferOffer{Encrypted: payload[0] == 1}
+	pos := 1
+
+	count := binary.LittleEndian.Uint32(payload[pos:])
+	pos += 4
+
+	offer.Files = make([]FileInfo, 0, count)
+	for i := uint32(0); i < count; i++ {
+		if pos+2 > len(payload) {
+			return nil, fmt.Errorf("protocol: truncated transfer offer")
+		}
+		pathLen := int(binary.LittleEndian.Uint16(payload[pos:]))
+		pos += 2
+
+		if pos+pathLen+8+4+sha256.Size > len(payload) {
+			return nil, fmt.Errorf("protocol: truncated transfer offer")
+		}
+
+		var fi FileInfo
+		fi.Path = string(payload[pos : pos+pathLen])
+		if err := validateOfferPath(fi.Path); err != nil {
+			return nil, err
+		}
+		pos += pathLen
+
+		fi.Size = int64(binary.LittleEndian.Uint64(payload[pos:]))
+		pos += 8
+
+		fi.Mode = fs.FileMode(binary.LittleEndian.Uint32(payload[pos:]))
+		pos += 4
+
+		copy(fi.SHA256[:], payload[pos:pos+sha256.Size])
+		pos += sha256.Size
+
+		offer.Files = append(offer.Files, fi)
+	}
+
+	return offer, nil
+}
+
+// validateOfferPath rejects any FileInfo.Path that could escape the
+// receiver's destination directory: empty paths, absolute paths, and paths
+// containing a ".." component. fi.Path always uses "/" as the separator (see
+// FileInfo), so this checks it with the "path" package rather than
+// "path/filepath" to stay independent of the host OS; a literal backslash is
+// rejected outright since receiver.go's filepath.FromSlash would treat it as
+// a real separator on a Windows receiver, letting it smuggle a traversal
+// past a "/"-only check.
+func validateOfferPath(p string) error {
+	if p == "" {
+		return fmt.Errorf("protocol: empty file path in offer")
+	}
+	if strings.Contains(p, "\\") {
+		return fmt.Errorf("protocol: unsafe file path in offer: %q", p)
+	}
+	if path.IsAbs(p) {
+		return fmt.Errorf("protocol: absolute file path in offer: %q", p)
+	}
+	clean := path.Clean(p)
+	if clean != p || clean == ".." || clean == "." || strings.HasPrefix(clean, "../") {
+		return fmt.Errorf("protocol: unsafe file path in offer: %q", p)
+	}
+	return nil
+}
+
+// FileStart precedes a file's contents on the wire. Size is the number of
+// bytes that follow, i.e. the file's total size minus ResumeFrom.
+type FileStart struct {
+	Index      uint32
+	Size       int64
+	Mode       fs.FileMode
+	ResumeFrom int64
+}
+
+func (s FileStart) encode() []byte {
+	buf := make([]byte, 4+8+4+8)
+	binary.LittleEndian.PutUint32(buf[0:], s.Index)
+	binary.LittleEndian.PutUint64(buf[4:], uint64(s.Size))
+	binary.LittleEndian.PutUint32(buf[12:], uint32(s.Mode))
+	binary.LittleEndian.PutUint64(buf[16:], uint64(s.ResumeFrom))
+	return buf
+}
+
+func decodeFileStart(payload []byte) (FileStart, error) {
+	if len(payload) != 24 {
+		return FileStart{}, fmt.Errorf("protocol: malformed file start packet")
+	}
+
+	return FileStart{
+		Index:      binary.LittleEndian.Uint32(payload[0:]),
+		Size:       int64(binary.LittleEndian.Uint64(payload[4:])),
+		Mode:       fs.FileMode(binary.LittleEndian.Uint32(payload[12:])),
+		ResumeFrom: int64(binary.LittleEndian.Uint64(payload[16:])),
+	}, nil
+}
+
+// AcceptOffer is the OfferAccept payload: for each file in the offer, the
+// byte offset the receiver already has on disk and wants the sender to
+// resume from (0 for a fresh file).
+type AcceptOffer struct {
+	ResumeFrom []int64
+}
+
+func (a AcceptOffer) encode() []byte {
+	buf := make([]byte, 4+8*len(a.ResumeFrom))
+	binary.LittleEndian.PutUint32(buf, uint32(len(a.ResumeFrom)))
+	pos := 4
+	for _, off := range a.ResumeFrom {
+		binary.LittleEndian.PutUint64(buf[pos:], uint64(off))
+		pos += 8
+	}
+	return buf
+}
+
+func decodeAcceptOffer(payload []byte) (AcceptOffer, error) {
+	if len(payload) < 4 {
+		return AcceptOffer{}, fmt.Errorf("protocol: truncated offer accept")
+	}
+
+	count := binary.LittleEndian.Uint32(payload)
+	if len(payload) != 4+8*int(count) {
+		return AcceptOffer{}, fmt.Errorf("protocol: truncated offer accept")
+	}
+
+	accept := AcceptOffer{ResumeFrom: make([]int64, count)}
+	pos := 4
+	for i := range accept.ResumeFrom {
+		accept.ResumeFrom[i] = int64(binary.LittleEndian.Uint64(payload[pos:]))
+		pos += 8
+	}
+
+	return accept, nil
+}
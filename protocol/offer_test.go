@@ -0,0 +1,37 @@
+package protocol
+
+import "testing"
+
+func TestValidateOfferPath(t *testing.T) {
+	valid := []string{
+		"file.txt",
+		"dir/file.txt",
+		"a/b/c/file.txt",
+		"..file.txt",
+		"file..txt",
+	}
+	for _, p := range valid {
+		t.Run(p, func(t *testing.T) {
+			if err := validateOfferPath(p); err != nil {
+				t.Fatalf("validateOfferPath(%q) = %v, want nil", p, err)
+			}
+		})
+	}
+
+	invalid := []string{
+		"",
+		"..",
+		"../escape.txt",
+		"dir/../../escape.txt",
+		"/etc/passwd",
+		`..\..\Users\victim\.ssh\authorized_keys`,
+		`dir\file.txt`,
+	}
+	for _, p := range invalid {
+		t.Run(p, func(t *testing.T) {
+			if err := validateOfferPath(p); err == nil {
+				t.Fatalf("validateOfferPath(%q) = nil, want an error", p)
+			}
+		})
+	}
+}
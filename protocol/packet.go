@@ -0,0 +1,102 @@
+// Package protocol implements the framed wire protocol used to offer and
+// transfer one or more files (optionally an entire directory tree) over a
+// single TCP connection.
+package protocol
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+// PacketType identifies the kind of payload carried by a packet.
+type PacketType byte
+
+const (
+	// PacketTransferOffer carries an encoded TransferOffer describing the
+	// files the sender wants to transfer.
+	PacketTransferOffer PacketType = iota + 1
+	// PacketOfferAccept acknowledges a TransferOffer; the receiver is ready.
+	PacketOfferAccept
+	// PacketOfferReject tells the sender the offer was declined.
+	PacketOfferReject
+	// PacketFileStart precedes a file's contents and carries a FileStart.
+	PacketFileStart
+	// PacketFileEnd follows a file's contents and carries its checksum.
+	PacketFileEnd
+	// PacketTransferDone marks the end of the transfer.
+	PacketTransferDone
+	// PacketKeyExchange carries an X25519 public key (and, from the sender,
+	// the AES-GCM nonce prefix) during the encrypted-mode handshake.
+	PacketKeyExchange
+)
+
+// headerSize is 1 byte of packet type plus an 8-byte LittleEndian length.
+const headerSize = 9
+
+// maxPacketPayloadSize bounds the allocation readPacket will make for a
+// control packet's payload (FileStart, FileEnd, KeyExchange, OfferAccept);
+// all of these are small fixed-size structs in practice. File contents
+// themselves never go through this path. This only guards against a peer
+// advertising an absurd length in the header and forcing an oversized
+// allocation.
+const maxPacketPayloadSize = 8 << 20 // 8 MiB
+
+// maxOfferPayloadSize bounds a TransferOffer packet's payload instead.
+// Unlike the other control packets, its size scales with the number of
+// files being offered (see TransferOffer.encode), so a large directory
+// tree needs a much higher ceiling than maxPacketPayloadSize. This is still
+// read and allocated before a caller's WithOfferAccept hook gets a chance to
+// reject it, so the ceiling is kept well short of maxPacketPayloadSize's
+// theoretical multiple of the same risk (a peer forcing an oversized
+// allocation off an unauthenticated header field) rather than raised as far
+// as the file count alone would justify; it comfortably covers a few
+// hundred thousand files, which is the case this exists for.
+const maxOfferPayloadSize = 64 << 20 // 64 MiB
+
+// writePacket writes a packet's header followed by its payload.
+func writePacket(w io.Writer, kind PacketType, payload []byte) error {
+	header := make([]byte, headerSize)
+	header[0] = byte(kind)
+	binary.LittleEndian.PutUint64(header[1:], uint64(len(payload)))
+
+	if _, err := w.Write(header); err != nil {
+		return err
+	}
+
+	if len(payload) == 0 {
+		return nil
+	}
+
+	_, err := w.Write(payload)
+	return err
+}
+
+// readPacket reads a single packet's header and payload.
+func readPacket(r io.Reader) (PacketType, []byte, error) {
+	header := make([]byte, headerSize)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return 0, nil, err
+	}
+
+	kind := PacketType(header[0])
+	length := binary.LittleEndian.Uint64(header[1:])
+	if length == 0 {
+		return kind, nil, nil
+	}
+
+	limit := uint64(maxPacketPayloadSize)
+	if kind == PacketTransferOffer {
+		limit = maxOfferPayloadSize
+	}
+	if length > limit {
+		return 0, nil, fmt.Errorf("protocol: packet payload of %d bytes exceeds the %d byte limit", length, limit)
+	}
+
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return 0, nil, err
+	}
+
+	return kind, payload, nil
+}
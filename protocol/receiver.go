@@ -0,0 +1,277 @@
+package protocol
+
+import (
+	"bytes"
+	"crypto/cipher"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+)
+
+// ReceiveOption configures a Receive call.
+type ReceiveOption func(*receiveConfig)
+
+type receiveConfig struct {
+	accept func(*TransferOffer) error
+}
+
+// WithOfferAccept installs a hook Receive calls right after decoding an
+// incoming TransferOffer and before accepting it. Returning a non-nil error
+// rejects the offer: Receive sends PacketOfferReject back to the sender
+// (which surfaces it as ErrOfferRejected from Send) and returns that same
+// error instead of proceeding. With no hook installed, every offer is
+// accepted, as before.
+func WithOfferAccept(fn func(*TransferOffer) error) ReceiveOption {
+	return func(c *receiveConfig) { c.accept = fn }
+}
+
+// Receive reads a TransferOffer from conn, accepts it, recreates the
+// directory structure under destDir and writes each file in the order the
+// sender offers them. A file that already exists at its destination path is
+// resumed from the byte offset already on disk, unless the offer asks for
+// encryption, which always starts from scratch.
+func Receive(conn net.Conn, destDir string, opts ...ReceiveOption) error {
+	var cfg receiveConfig
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	kind, payload, err := readPacket(conn)
+	if err != nil {
+		return err
+	}
+	if kind != PacketTransferOffer {
+		return ErrUnexpectedPacket
+	}
+
+	offer, err := decodeTransferOffer(payload)
+	if err != nil {
+		return err
+	}
+
+	if cfg.accept != nil {
+		if rejectErr := cfg.accept(offer); rejectErr != nil {
+			if err := writePacket(conn, PacketOfferReject, nil); err != nil {
+				return fmt.Errorf("protocol: offer rejected (%v), but failed to notify sender: %w", rejectErr, err)
+			}
+			return rejectErr
+		}
+	}
+
+	accept := AcceptOffer{ResumeFrom: make([]int64, len(offer.Files))}
+	for i, fi := range offer.Files {
+		dir := filepath.Join(destDir, filepath.FromSlash(filepath.Dir(fi.Path)))
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return err
+		}
+
+		if !offer.Encrypted {
+			accept.ResumeFrom[i] = resumeOffset(filepath.Join(destDir, filepath.FromSlash(fi.Path)), fi.Size)
+		}
+	}
+
+	if err := writePacket(conn, PacketOfferAccept, accept.encode()); err != nil {
+		return err
+	}
+
+	var (
+		aead        cipher.AEAD
+		noncePrefix [noncePrefixSize]byte
+		counter     *uint32
+	)
+	if offer.Encrypted {
+		aead, noncePrefix, err = handshakeReceiver(conn)
+		if err != nil {
+			return err
+		}
+		counter = new(uint32)
+	}
+
+	for i, fi := range offer.Files {
+		if offer.Encrypted {
+			if err := receiveOneEncrypted(conn, destDir, uint32(i), fi, aead, noncePrefix, counter); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := receiveOne(conn, destDir, uint32(i), fi); err != nil {
+			return err
+		}
+	}
+
+	kind, _, err = readPacket(conn)
+	if err != nil {
+		return err
+	}
+	if kind != PacketTransferDone {
+		return ErrUnexpectedPacket
+	}
+
+	return nil
+}
+
+// resumeOffset returns how much of path is already on disk, capped at
+// wantSize, or 0 if the file doesn't exist or is already complete-sized but
+// unverified (it will be re-hashed anyway once fully received).
+func resumeOffset(path string, wantSize int64) int64 {
+	info, err := os.Stat(path)
+	if err != nil {
+		return 0
+	}
+	if info.Size() >= wantSize {
+		return 0
+	}
+	return info.Size()
+}
+
+func receiveOne(conn net.Conn, destDir string, index uint32, fi FileInfo) error {
+	start, path, file, err := beginReceive(conn, destDir, index, fi)
+	if err != nil {
+		return err
+	}
+
+	var sum [sha256.Size]byte
+	if start.ResumeFrom == 0 {
+		// Fresh file: hash the bytes as they're written instead of reading
+		// the whole thing back from disk afterwards.
+		hasher := sha256.New()
+		_, err = io.CopyN(io.MultiWriter(file, hasher), conn, start.Size)
+		file.Close()
+		if err != nil {
+			// A plain I/O error (e.g. a dropped connection) leaves the
+			// partial file on disk so a later resume can pick up from it;
+			// only a confirmed protocol violation or checksum mismatch
+			// removes it.
+			return err
+		}
+		copy(sum[:], hasher.Sum(nil))
+	} else {
+		_, err = io.CopyN(file, conn, start.Size)
+		file.Close()
+		if err != nil {
+			return err
+		}
+	}
+
+	checksum, err := readFileEnd(conn, path)
+	if err != nil {
+		return err
+	}
+
+	if start.ResumeFrom != 0 {
+		// A resumed transfer only streamed the bytes after ResumeFrom, so
+		// there's no hash state covering what was already on disk; verify
+		// the whole file against the digest the sender promised in the
+		// offer (and echoed in FileEnd) instead.
+		sum, err = hashFile(path)
+		if err != nil {
+			return err
+		}
+	}
+	if !bytes.Equal(sum[:], checksum) {
+		os.Remove(path)
+		return ErrChecksumMismatch
+	}
+
+	return nil
+}
+
+func receiveOneEncrypted(conn net.Conn, destDir string, index uint32, fi FileInfo, aead cipher.AEAD, noncePrefix [noncePrefixSize]byte, counter *uint32) error {
+	start, path, file, err := beginReceive(conn, destDir, index, fi)
+	if err != nil {
+		return err
+	}
+
+	// Encrypted transfers always start from scratch (see Receive), so the
+	// hash can always be streamed alongside the plaintext instead of
+	// reading the file back from disk afterwards.
+	hasher := sha256.New()
+	err = receiveEncrypted(conn, io.MultiWriter(file, hasher), start.Size, aead, noncePrefix, counter)
+	file.Close()
+	if err != nil {
+		// A failed AEAD open means the chunk was tampered with or the
+		// stream is corrupt, not just a dropped connection, so the partial
+		// file can't be trusted for a future resume either. Any other
+		// error (e.g. a dropped connection) leaves the partial file alone.
+		if errors.Is(err, ErrDecryptionFailed) {
+			os.Remove(path)
+		}
+		return err
+	}
+
+	checksum, err := readFileEnd(conn, path)
+	if err != nil {
+		return err
+	}
+
+	var sum [sha256.Size]byte
+	copy(sum[:], hasher.Sum(nil))
+	if !bytes.Equal(sum[:], checksum) {
+		os.Remove(path)
+		return ErrChecksumMismatch
+	}
+
+	return nil
+}
+
+// beginReceive reads and validates a file's FileStart packet and opens its
+// destination file, seeked to the resume offset.
+func beginReceive(conn net.Conn, destDir string, index uint32, fi FileInfo) (FileStart, string, *os.File, error) {
+	kind, payload, err := readPacket(conn)
+	if err != nil {
+		return FileStart{}, "", nil, err
+	}
+	if kind != PacketFileStart {
+		return FileStart{}, "", nil, ErrUnexpectedPacket
+	}
+
+	start, err := decodeFileStart(payload)
+	if err != nil {
+		return FileStart{}, "", nil, err
+	}
+	if start.Index != index {
+		return FileStart{}, "", nil, fmt.Errorf("protocol: out-of-order file, got index %d want %d", start.Index, index)
+	}
+
+	path := filepath.Join(destDir, filepath.FromSlash(fi.Path))
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE, start.Mode.Perm())
+	if err != nil {
+		return FileStart{}, "", nil, err
+	}
+
+	// A pre-existing destination file may be longer than fi.Size (a stale
+	// file from an earlier, different transfer). Truncate to the final
+	// expected length so the post-transfer whole-file hash check isn't
+	// corrupted by leftover trailing bytes.
+	if err := file.Truncate(fi.Size); err != nil {
+		file.Close()
+		return FileStart{}, "", nil, err
+	}
+
+	if _, err := file.Seek(start.ResumeFrom, io.SeekStart); err != nil {
+		file.Close()
+		return FileStart{}, "", nil, err
+	}
+
+	return start, path, file, nil
+}
+
+// readFileEnd reads the FileEnd packet following a file's contents,
+// returning its checksum. A plain I/O error leaves path in place for a
+// later resume; an unexpected packet type is a protocol violation and
+// removes it, since the stream can no longer be trusted to resync.
+func readFileEnd(conn net.Conn, path string) ([]byte, error) {
+	kind, payload, err := readPacket(conn)
+	if err != nil {
+		return nil, err
+	}
+	if kind != PacketFileEnd {
+		os.Remove(path)
+		return nil, ErrUnexpectedPacket
+	}
+	return payload, nil
+}
@@ -0,0 +1,159 @@
+package protocol
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+)
+
+// BuildOffer walks rootDir and builds a TransferOffer describing every
+// regular file beneath it, relative to rootDir.
+func BuildOffer(rootDir string) (*TransferOffer, error) {
+	var offer TransferOffer
+
+	err := filepath.WalkDir(rootDir, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(rootDir, path)
+		if err != nil {
+			return err
+		}
+
+		sum, err := hashFile(path)
+		if err != nil {
+			return err
+		}
+
+		offer.Files = append(offer.Files, FileInfo{
+			Path:   filepath.ToSlash(rel),
+			Size:   info.Size(),
+			Mode:   info.Mode(),
+			SHA256: sum,
+		})
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &offer, nil
+}
+
+func hashFile(path string) ([sha256.Size]byte, error) {
+	var sum [sha256.Size]byte
+
+	f, err := os.Open(path)
+	if err != nil {
+		return sum, err
+	}
+	defer f.Close()
+
+	hasher := sha256.New()
+	if _, err := io.Copy(hasher, f); err != nil {
+		return sum, err
+	}
+
+	copy(sum[:], hasher.Sum(nil))
+	return sum, nil
+}
+
+// Send offers the files under rootDir to the peer on conn and, once
+// accepted, streams each one through a Transport. The transport defaults to
+// the fastest one conn supports (see defaultTransport) and can be forced
+// with WithTransport, e.g. for tests and benchmarks. WithEncryption asks for
+// an AES-256-GCM encrypted transport instead, negotiated with the receiver
+// right after the offer is accepted.
+func Send(conn net.Conn, rootDir string, opts ...Option) error {
+	var cfg sendConfig
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	if cfg.transport == nil {
+		cfg.transport = defaultTransport(conn)
+	}
+
+	offer, err := BuildOffer(rootDir)
+	if err != nil {
+		return err
+	}
+	offer.Encrypted = cfg.encrypt
+
+	if err := writePacket(conn, PacketTransferOffer, offer.encode()); err != nil {
+		return err
+	}
+
+	kind, payload, err := readPacket(conn)
+	if err != nil {
+		return err
+	}
+	switch kind {
+	case PacketOfferAccept:
+	case PacketOfferReject:
+		return ErrOfferRejected
+	default:
+		return ErrUnexpectedPacket
+	}
+
+	accept, err := decodeAcceptOffer(payload)
+	if err != nil {
+		return err
+	}
+	if len(accept.ResumeFrom) != len(offer.Files) {
+		return fmt.Errorf("protocol: offer accept covers %d files, want %d", len(accept.ResumeFrom), len(offer.Files))
+	}
+
+	transport := cfg.transport
+	if cfg.encrypt {
+		aead, noncePrefix, err := handshakeSender(conn)
+		if err != nil {
+			return err
+		}
+		transport = EncryptedTransport{AEAD: aead, NoncePrefix: noncePrefix, Counter: new(uint32)}
+	}
+
+	for i, fi := range offer.Files {
+		if err := sendOne(transport, conn, rootDir, uint32(i), fi, accept.ResumeFrom[i]); err != nil {
+			return err
+		}
+	}
+
+	return writePacket(conn, PacketTransferDone, nil)
+}
+
+func sendOne(transport Transport, conn net.Conn, rootDir string, index uint32, fi FileInfo, resumeFrom int64) error {
+	path := filepath.Join(rootDir, filepath.FromSlash(fi.Path))
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	remaining := fi.Size - resumeFrom
+	start := FileStart{Index: index, Size: remaining, Mode: fi.Mode, ResumeFrom: resumeFrom}
+	if err := writePacket(conn, PacketFileStart, start.encode()); err != nil {
+		return err
+	}
+
+	if err := transport.Send(conn, file, resumeFrom, remaining); err != nil {
+		return fmt.Errorf("protocol: send %s: %w", fi.Path, err)
+	}
+
+	return writePacket(conn, PacketFileEnd, fi.SHA256[:])
+}
@@ -0,0 +1,85 @@
+package protocol
+
+import (
+	"io"
+	"net"
+	"os"
+	"sync"
+)
+
+// defaultBufferSize is the size of buffers drawn from bufferPool by
+// BufferedTransport when none is configured explicitly.
+const defaultBufferSize = 64 * 1024
+
+// Transport sends the bytes of a range of file on conn, starting at offset
+// and covering size bytes.
+type Transport interface {
+	Send(conn net.Conn, file *os.File, offset, size int64) error
+}
+
+// Option configures a Send call.
+type Option func(*sendConfig)
+
+type sendConfig struct {
+	transport Transport
+	encrypt   bool
+}
+
+// WithTransport forces a specific Transport instead of letting Send pick one
+// based on the connection type. Mainly useful for tests and benchmarks.
+func WithTransport(t Transport) Option {
+	return func(c *sendConfig) { c.transport = t }
+}
+
+// WithEncryption negotiates an AES-256-GCM encrypted transport with the
+// receiver instead of sending the offer's files in the clear. It overrides
+// any Transport set with WithTransport, since AES-GCM needs the plaintext in
+// userspace and can't use the zero-copy sendfile(2) path.
+func WithEncryption() Option {
+	return func(c *sendConfig) { c.encrypt = true }
+}
+
+// BufferedTransport copies a file to conn through a pooled, reusable buffer.
+// It works with any net.Conn, making it the fallback for connections that
+// don't support the zero-copy sendfile(2) fast path (TLS, Unix sockets,
+// non-Linux hosts, ...).
+type BufferedTransport struct {
+	// BufferSize overrides the size of buffers drawn from the pool. Zero
+	// means defaultBufferSize.
+	BufferSize int
+}
+
+var bufferPool = sync.Pool{
+	New: func() any {
+		buf := make([]byte, defaultBufferSize)
+		return &buf
+	},
+}
+
+func (t BufferedTransport) Send(conn net.Conn, file *os.File, offset, size int64) error {
+	if _, err := file.Seek(offset, io.SeekStart); err != nil {
+		return err
+	}
+
+	bufSize := t.BufferSize
+	if bufSize == 0 {
+		bufSize = defaultBufferSize
+	}
+
+	var buf []byte
+	if bufSize == defaultBufferSize {
+		bufp := bufferPool.Get().(*[]byte)
+		defer bufferPool.Put(bufp)
+		buf = *bufp
+	} else {
+		buf = make([]byte, bufSize)
+	}
+
+	_, err := io.CopyBuffer(conn, io.LimitReader(file, size), buf)
+	return err
+}
+
+// defaultTransport picks a Transport for conn when the caller doesn't force
+// one with WithTransport. It is implemented per-OS in transport_linux.go and
+// transport_other.go: Linux prefers the zero-copy SendfileTransport for
+// *net.TCPConn, everything else falls back to BufferedTransport.
@@ -0,0 +1,42 @@
+//go:build linux
+
+package protocol
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"syscall"
+)
+
+// SendfileTransport sends a file range using the Linux sendfile(2) syscall,
+// copying directly between the file and socket descriptors in the kernel
+// without staging the bytes in userspace. It only works on *net.TCPConn.
+type SendfileTransport struct{}
+
+func (SendfileTransport) Send(conn net.Conn, file *os.File, offset, size int64) error {
+	tcpConn, ok := conn.(*net.TCPConn)
+	if !ok {
+		return fmt.Errorf("protocol: SendfileTransport requires a *net.TCPConn, got %T", conn)
+	}
+
+	tcpFile, err := tcpConn.File()
+	if err != nil {
+		return err
+	}
+	defer tcpFile.Close()
+
+	// Sendfile's offset argument both seeds the source offset and is
+	// updated in place, so pass a pointer seeded with the resume point
+	// rather than nil.
+	off := offset
+	_, err = syscall.Sendfile(int(tcpFile.Fd()), int(file.Fd()), &off, int(size))
+	return err
+}
+
+func defaultTransport(conn net.Conn) Transport {
+	if _, ok := conn.(*net.TCPConn); ok {
+		return SendfileTransport{}
+	}
+	return BufferedTransport{}
+}
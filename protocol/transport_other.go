@@ -0,0 +1,9 @@
+//go:build !linux
+
+package protocol
+
+import "net"
+
+func defaultTransport(conn net.Conn) Transport {
+	return BufferedTransport{}
+}